@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorser
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/common/ccprovider"
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/pkg/errors"
+)
+
+// lsccNamespace is the state namespace LSCC stores ChaincodeData under,
+// keyed by chaincode name.
+const lsccNamespace = "lscc"
+
+// ChaincodeDefinitionGetter resolves the ChaincodeDefinition for an
+// instantiated chaincode. It exists so SupportImpl can be given a mock in
+// tests instead of always going through a live LSCC lookup.
+type ChaincodeDefinitionGetter interface {
+	ChaincodeDefinition(name string, qe ledger.QueryExecutor) (ccprovider.ChaincodeDefinition, error)
+}
+
+// InstantiatedCCStore resolves the on-disk package and instantiation
+// metadata for chaincodes that have already been installed and
+// instantiated, without SupportImpl calling into ccprovider directly.
+type InstantiatedCCStore interface {
+	GetChaincodeDeploymentSpecFS(cds *pb.ChaincodeDeploymentSpec) (*pb.ChaincodeDeploymentSpec, error)
+	IsJavaCC(buf []byte) (bool, error)
+	CheckInstantiationPolicy(name, version string, cd ccprovider.ChaincodeDefinition) error
+}
+
+// lsccChaincodeDefinitionGetter is the production ChaincodeDefinitionGetter.
+// It reads the chaincode's ChaincodeData directly out of the lscc
+// namespace through the supplied query executor, so resolving a
+// definition no longer requires a real chaincode invocation.
+type lsccChaincodeDefinitionGetter struct{}
+
+// NewLSCCChaincodeDefinitionGetter returns the production
+// ChaincodeDefinitionGetter backed by LSCC state.
+func NewLSCCChaincodeDefinitionGetter() ChaincodeDefinitionGetter {
+	return &lsccChaincodeDefinitionGetter{}
+}
+
+func (*lsccChaincodeDefinitionGetter) ChaincodeDefinition(name string, qe ledger.QueryExecutor) (ccprovider.ChaincodeDefinition, error) {
+	ccDataBytes, err := qe.GetState(lsccNamespace, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not retrieve state for chaincode %s", name)
+	}
+	if ccDataBytes == nil {
+		return nil, errors.Errorf("chaincode %s not found in lscc namespace", name)
+	}
+
+	ccData := &ccprovider.ChaincodeData{}
+	if err := proto.Unmarshal(ccDataBytes, ccData); err != nil {
+		return nil, errors.Wrapf(err, "could not unmarshal state for chaincode %s", name)
+	}
+	return ccData, nil
+}
+
+// lsccInstantiatedCCStore is the production InstantiatedCCStore. It keeps
+// the same behavior SupportImpl used to implement inline, just moved
+// behind an interface.
+type lsccInstantiatedCCStore struct{}
+
+// NewLSCCInstantiatedCCStore returns the production InstantiatedCCStore
+// backed by the chaincode filesystem store.
+func NewLSCCInstantiatedCCStore() InstantiatedCCStore {
+	return &lsccInstantiatedCCStore{}
+}
+
+// GetChaincodeDeploymentSpecFS returns the CCPackage from the fs
+func (*lsccInstantiatedCCStore) GetChaincodeDeploymentSpecFS(cds *pb.ChaincodeDeploymentSpec) (*pb.ChaincodeDeploymentSpec, error) {
+	ccpack, err := ccprovider.GetChaincodeFromFS(cds.ChaincodeSpec.ChaincodeId.Name, cds.ChaincodeSpec.ChaincodeId.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get chaincode from fs")
+	}
+	return ccpack.GetDepSpec(), nil
+}
+
+// IsJavaCC returns true if the CDS package bytes describe a chaincode
+// that requires the java runtime environment to execute
+func (*lsccInstantiatedCCStore) IsJavaCC(buf []byte) (bool, error) {
+	ccpack, err := ccprovider.GetCCPackage(buf)
+	if err != nil {
+		return false, err
+	}
+	cds := ccpack.GetDepSpec()
+	return cds.ChaincodeSpec.Type == pb.ChaincodeSpec_JAVA, nil
+}
+
+// CheckInstantiationPolicy returns an error if the instantiation in the
+// supplied ChaincodeDefinition differs from the instantiation policy
+// stored on the ledger
+func (*lsccInstantiatedCCStore) CheckInstantiationPolicy(name, version string, cd ccprovider.ChaincodeDefinition) error {
+	return ccprovider.CheckInstantiationPolicy(name, version, cd.(*ccprovider.ChaincodeData))
+}
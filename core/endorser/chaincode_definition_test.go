@@ -0,0 +1,74 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorser
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/common/ccprovider"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueryExecutor embeds ledger.QueryExecutor as a zero value so it
+// satisfies the (large) interface at compile time, and overrides only
+// GetState, the single method lsccChaincodeDefinitionGetter calls.
+type fakeQueryExecutor struct {
+	ledger.QueryExecutor
+	state map[string][]byte
+	err   error
+}
+
+func (f *fakeQueryExecutor) GetState(namespace, key string) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.state[namespace+"\x00"+key], nil
+}
+
+func TestLSCCChaincodeDefinitionGetter(t *testing.T) {
+	getter := NewLSCCChaincodeDefinitionGetter()
+
+	t.Run("found", func(t *testing.T) {
+		ccData := &ccprovider.ChaincodeData{Name: "mycc", Version: "1.0"}
+		ccDataBytes, err := proto.Marshal(ccData)
+		require.NoError(t, err)
+
+		qe := &fakeQueryExecutor{state: map[string][]byte{
+			lsccNamespace + "\x00mycc": ccDataBytes,
+		}}
+
+		def, err := getter.ChaincodeDefinition("mycc", qe)
+		require.NoError(t, err)
+		assert.Equal(t, "mycc", def.CCName())
+		assert.Equal(t, "1.0", def.CCVersion())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		qe := &fakeQueryExecutor{state: map[string][]byte{}}
+		_, err := getter.ChaincodeDefinition("missing", qe)
+		require.EqualError(t, err, "chaincode missing not found in lscc namespace")
+	})
+
+	t.Run("GetState error", func(t *testing.T) {
+		qe := &fakeQueryExecutor{err: assert.AnError}
+		_, err := getter.ChaincodeDefinition("mycc", qe)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not retrieve state for chaincode mycc")
+	})
+
+	t.Run("unmarshal error", func(t *testing.T) {
+		qe := &fakeQueryExecutor{state: map[string][]byte{
+			lsccNamespace + "\x00mycc": {0xFF, 0xFF, 0xFF},
+		}}
+		_, err := getter.ChaincodeDefinition("mycc", qe)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not unmarshal state for chaincode mycc")
+	})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorser
+
+import (
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// replicaResult is the observable outcome of running one input against
+// one ChaincodeSupport replica, reduced to the fields the quorum check
+// compares: status, a hash of the response payload, and a hash of the
+// read/write set the transaction simulator recorded.
+type replicaResult struct {
+	supportName string
+	response    *pb.Response
+	event       *pb.ChaincodeEvent
+	payloadHash [32]byte
+	rwsetHash   [32]byte
+	err         error
+}
+
+// ReplicaDigest is the fingerprint of a single replica's execution of a
+// proposal. It is reported only inside NonDeterministicExecutionError
+// (one per replica) so the caller can log or alert on the disagreement;
+// a successful quorum decision returns no digests.
+type ReplicaDigest struct {
+	SupportName string
+	Status      int32
+	PayloadHash [32]byte
+	RWSetHash   [32]byte
+}
+
+// NonDeterministicExecutionError is returned by SupportImpl's replicated
+// execution path when no response reaches quorum agreement across
+// replicas. Every Reconcile call that fails to find quorum allocates a
+// fresh instance, so callers must check for it with errors.As rather
+// than comparing against a sentinel value; it carries the per-replica
+// digests collected before the quorum check failed.
+type NonDeterministicExecutionError struct {
+	Digests []ReplicaDigest
+}
+
+func (e *NonDeterministicExecutionError) Error() string {
+	return "non-deterministic chaincode execution: no quorum of replicas agreed on status, payload hash and read/write set hash"
+}
+
+// ReconciliationPolicy decides, from the set of replica results collected
+// for a single input, whether a canonical response can be established
+// and what it is.
+type ReconciliationPolicy interface {
+	Reconcile(results []replicaResult) (*pb.Response, *pb.ChaincodeEvent, error)
+}
+
+// QuorumReconciliationPolicy picks the response that at least Quorum
+// replicas agree on, comparing status, payload hash and read/write set
+// hash together as a single key.
+type QuorumReconciliationPolicy struct {
+	Quorum int
+}
+
+// Reconcile implements ReconciliationPolicy.
+func (p *QuorumReconciliationPolicy) Reconcile(results []replicaResult) (*pb.Response, *pb.ChaincodeEvent, error) {
+	type key struct {
+		status      int32
+		payloadHash [32]byte
+		rwsetHash   [32]byte
+	}
+
+	counts := make(map[key]int, len(results))
+	winners := make(map[key]replicaResult, len(results))
+	digests := make([]ReplicaDigest, 0, len(results))
+
+	for _, r := range results {
+		var status int32
+		if r.response != nil {
+			status = r.response.Status
+		}
+
+		k := key{status: status, payloadHash: r.payloadHash, rwsetHash: r.rwsetHash}
+		counts[k]++
+		winners[k] = r
+
+		digests = append(digests, ReplicaDigest{
+			SupportName: r.supportName,
+			Status:      status,
+			PayloadHash: r.payloadHash,
+			RWSetHash:   r.rwsetHash,
+		})
+	}
+
+	for k, count := range counts {
+		if count >= p.Quorum {
+			winner := winners[k]
+			return winner.response, winner.event, winner.err
+		}
+	}
+
+	return nil, nil, &NonDeterministicExecutionError{Digests: digests}
+}
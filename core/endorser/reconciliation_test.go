@@ -0,0 +1,75 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorser
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuorumReconciliationPolicyReconcile(t *testing.T) {
+	payloadHash := sha256.Sum256([]byte("payload"))
+	rwsetHash := sha256.Sum256([]byte("rwset"))
+
+	agreeing := replicaResult{
+		supportName: "peer0",
+		response:    &pb.Response{Status: 200, Payload: []byte("payload")},
+		event:       &pb.ChaincodeEvent{EventName: "event"},
+		payloadHash: payloadHash,
+		rwsetHash:   rwsetHash,
+	}
+	agreeing2 := agreeing
+	agreeing2.supportName = "peer1"
+
+	dissenting := replicaResult{
+		supportName: "peer2",
+		response:    &pb.Response{Status: 200, Payload: []byte("different")},
+		payloadHash: sha256.Sum256([]byte("different")),
+		rwsetHash:   rwsetHash,
+	}
+
+	t.Run("quorum reached", func(t *testing.T) {
+		policy := &QuorumReconciliationPolicy{Quorum: 2}
+		resp, event, err := policy.Reconcile([]replicaResult{agreeing, dissenting, agreeing2})
+		require.NoError(t, err)
+		assert.Equal(t, agreeing.response, resp)
+		assert.Equal(t, agreeing.event, event)
+	})
+
+	t.Run("no quorum", func(t *testing.T) {
+		policy := &QuorumReconciliationPolicy{Quorum: 2}
+		_, _, err := policy.Reconcile([]replicaResult{agreeing, dissenting})
+		require.Error(t, err)
+
+		var ndErr *NonDeterministicExecutionError
+		require.True(t, errors.As(err, &ndErr))
+		assert.Len(t, ndErr.Digests, 2)
+	})
+
+	t.Run("replica error propagates through the winning result", func(t *testing.T) {
+		failing := replicaResult{supportName: "peer0", err: errors.New("execution failed")}
+		failing2 := failing
+
+		policy := &QuorumReconciliationPolicy{Quorum: 2}
+		_, _, err := policy.Reconcile([]replicaResult{failing, failing2})
+		require.EqualError(t, err, "execution failed")
+	})
+
+	t.Run("fresh instance each call, no shared sentinel", func(t *testing.T) {
+		policy := &QuorumReconciliationPolicy{Quorum: 2}
+		_, _, err1 := policy.Reconcile([]replicaResult{agreeing, dissenting})
+		_, _, err2 := policy.Reconcile([]replicaResult{agreeing, dissenting})
+		require.Error(t, err1)
+		require.Error(t, err2)
+		assert.NotSame(t, err1, err2)
+	})
+}
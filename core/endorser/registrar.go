@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorser
+
+import (
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode"
+	"github.com/pkg/errors"
+)
+
+// ChaincodeSupportRegistrar resolves a *chaincode.ChaincodeSupport by the
+// name of the container/worker it backs, analogous to the orderer's
+// channel Registrar. It replaces picking a support out of a
+// []*chaincode.ChaincodeSupport by array index, which broke silently once
+// the number of proposals in a batch exceeded the number of supports.
+type ChaincodeSupportRegistrar struct {
+	supports     map[string]*chaincode.ChaincodeSupport
+	defaultName  string
+	systemCCName string
+}
+
+// NewChaincodeSupportRegistrar builds a registrar from the named supports.
+// defaultName designates the support ordinary proposals route to absent a
+// more specific dispatch policy, and systemCCName designates the support
+// that hosts system chaincode invocations (cscc, lscc, ...). Both names
+// must be present in supports.
+func NewChaincodeSupportRegistrar(supports map[string]*chaincode.ChaincodeSupport, defaultName, systemCCName string) (*ChaincodeSupportRegistrar, error) {
+	if _, ok := supports[defaultName]; !ok {
+		return nil, errors.Errorf("no chaincode support registered under default name %s", defaultName)
+	}
+	if _, ok := supports[systemCCName]; !ok {
+		return nil, errors.Errorf("no chaincode support registered under system chaincode name %s", systemCCName)
+	}
+
+	return &ChaincodeSupportRegistrar{
+		supports:     supports,
+		defaultName:  defaultName,
+		systemCCName: systemCCName,
+	}, nil
+}
+
+// Get returns the ChaincodeSupport registered under name, or an error if
+// none is registered rather than panicking on an out-of-range index.
+func (r *ChaincodeSupportRegistrar) Get(name string) (*chaincode.ChaincodeSupport, error) {
+	support, ok := r.supports[name]
+	if !ok {
+		return nil, errors.Errorf("no chaincode support registered under name %s", name)
+	}
+	return support, nil
+}
+
+// Default returns the ChaincodeSupport used for ordinary proposals when
+// no more specific routing policy applies.
+func (r *ChaincodeSupportRegistrar) Default() *chaincode.ChaincodeSupport {
+	return r.supports[r.defaultName]
+}
+
+// ForSystemCC returns the ChaincodeSupport designated to host system
+// chaincode invocations such as GetConfigBlock, cscc, and lscc.
+func (r *ChaincodeSupportRegistrar) ForSystemCC() *chaincode.ChaincodeSupport {
+	return r.supports[r.systemCCName]
+}
+
+// Names returns the registered support names in a stable, sorted order so
+// index-based dispatch policies (e.g. round-robin) are deterministic.
+func (r *ChaincodeSupportRegistrar) Names() []string {
+	names := make([]string, 0, len(r.supports))
+	for name := range r.supports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DispatchPolicy picks which ChaincodeSupport handles proposal index of a
+// batch for the given channel/txid. Implementations may spread load
+// across supports (round-robin), keep a channel pinned to one support
+// (channel-sticky), or shard deterministically on the txid.
+type DispatchPolicy interface {
+	Choose(registrar *ChaincodeSupportRegistrar, index int, channelID, txid string) (*chaincode.ChaincodeSupport, error)
+}
+
+// RoundRobinDispatchPolicy cycles through the registrar's named supports
+// in sorted order.
+type RoundRobinDispatchPolicy struct{}
+
+// Choose implements DispatchPolicy.
+func (RoundRobinDispatchPolicy) Choose(registrar *ChaincodeSupportRegistrar, index int, channelID, txid string) (*chaincode.ChaincodeSupport, error) {
+	names := registrar.Names()
+	if len(names) == 0 {
+		return nil, errors.New("no chaincode support registered")
+	}
+	return registrar.Get(names[index%len(names)])
+}
@@ -0,0 +1,100 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorser
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChaincodeSupportRegistrar(t *testing.T) {
+	def := &chaincode.ChaincodeSupport{}
+	sys := &chaincode.ChaincodeSupport{}
+	supports := map[string]*chaincode.ChaincodeSupport{"default": def, "system": sys}
+
+	t.Run("missing default", func(t *testing.T) {
+		_, err := NewChaincodeSupportRegistrar(supports, "missing", "system")
+		require.EqualError(t, err, "no chaincode support registered under default name missing")
+	})
+
+	t.Run("missing system", func(t *testing.T) {
+		_, err := NewChaincodeSupportRegistrar(supports, "default", "missing")
+		require.EqualError(t, err, "no chaincode support registered under system chaincode name missing")
+	})
+
+	t.Run("success", func(t *testing.T) {
+		r, err := NewChaincodeSupportRegistrar(supports, "default", "system")
+		require.NoError(t, err)
+		assert.Same(t, def, r.Default())
+		assert.Same(t, sys, r.ForSystemCC())
+	})
+}
+
+func TestChaincodeSupportRegistrarGet(t *testing.T) {
+	foo := &chaincode.ChaincodeSupport{}
+	r, err := NewChaincodeSupportRegistrar(map[string]*chaincode.ChaincodeSupport{
+		"default": foo,
+		"system":  foo,
+	}, "default", "system")
+	require.NoError(t, err)
+
+	support, err := r.Get("default")
+	require.NoError(t, err)
+	assert.Same(t, foo, support)
+
+	_, err = r.Get("bogus")
+	require.EqualError(t, err, "no chaincode support registered under name bogus")
+}
+
+func TestChaincodeSupportRegistrarNames(t *testing.T) {
+	one := &chaincode.ChaincodeSupport{}
+	r, err := NewChaincodeSupportRegistrar(map[string]*chaincode.ChaincodeSupport{
+		"default": one,
+		"system":  one,
+		"charlie": one,
+		"alpha":   one,
+		"bravo":   one,
+	}, "default", "system")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"alpha", "bravo", "charlie", "default", "system"}, r.Names())
+}
+
+func TestRoundRobinDispatchPolicy(t *testing.T) {
+	a := &chaincode.ChaincodeSupport{}
+	b := &chaincode.ChaincodeSupport{}
+	r, err := NewChaincodeSupportRegistrar(map[string]*chaincode.ChaincodeSupport{
+		"a": a,
+		"b": b,
+	}, "a", "b")
+	require.NoError(t, err)
+
+	policy := RoundRobinDispatchPolicy{}
+
+	support, err := policy.Choose(r, 0, "mychannel", "tx1")
+	require.NoError(t, err)
+	assert.Same(t, a, support)
+
+	support, err = policy.Choose(r, 1, "mychannel", "tx1")
+	require.NoError(t, err)
+	assert.Same(t, b, support)
+
+	support, err = policy.Choose(r, 2, "mychannel", "tx1")
+	require.NoError(t, err)
+	assert.Same(t, a, support)
+}
+
+func TestRoundRobinDispatchPolicyNoSupports(t *testing.T) {
+	empty := &ChaincodeSupportRegistrar{}
+	policy := RoundRobinDispatchPolicy{}
+
+	_, err := policy.Choose(empty, 0, "mychannel", "tx1")
+	require.EqualError(t, err, "no chaincode support registered")
+}
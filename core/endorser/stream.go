@@ -0,0 +1,189 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorser
+
+import (
+	"context"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/common/ccprovider"
+	"github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/pkg/errors"
+)
+
+// defaultMaxInflight bounds concurrent in-flight proposals on a stream
+// when SupportImpl.MaxInflight is left at its zero value.
+const defaultMaxInflight = 256
+
+// errorResponse builds the ProposalResponse returned for a proposal that
+// failed before reaching a chaincode, so stream and unary callers report
+// failures the same way Execute's own errors are reported.
+func errorResponse(err error) *pb.ProposalResponse {
+	return &pb.ProposalResponse{
+		Response: &pb.Response{
+			Status:  500,
+			Message: err.Error(),
+		},
+	}
+}
+
+// processSignedProposal decodes and simulates a single SignedProposal
+// through the same Registrar-backed execution engine Execute uses, and
+// returns the chaincode response wrapped in a ProposalResponse; the
+// response is not signed and carries no endorsement. It is wired here as a function variable (rather than
+// called directly) so that the peer's gRPC service -
+// core/endorser.Endorser, which embeds SupportImpl but lives outside
+// this package slice - can plug in its real proposal pipeline, and so
+// tests can stub it out without a live ledger.
+var processSignedProposal = func(ctx context.Context, s *SupportImpl, signedProp *pb.SignedProposal) *pb.ProposalResponse {
+	prop := &pb.Proposal{}
+	if err := proto.Unmarshal(signedProp.ProposalBytes, prop); err != nil {
+		return errorResponse(errors.Wrap(err, "could not unmarshal proposal"))
+	}
+
+	hdr := &common.Header{}
+	if err := proto.Unmarshal(prop.Header, hdr); err != nil {
+		return errorResponse(errors.Wrap(err, "could not unmarshal header"))
+	}
+
+	chdr := &common.ChannelHeader{}
+	if err := proto.Unmarshal(hdr.ChannelHeader, chdr); err != nil {
+		return errorResponse(errors.Wrap(err, "could not unmarshal channel header"))
+	}
+
+	shdr := &common.SignatureHeader{}
+	if err := proto.Unmarshal(hdr.SignatureHeader, shdr); err != nil {
+		return errorResponse(errors.Wrap(err, "could not unmarshal signature header"))
+	}
+
+	payload := &pb.ChaincodeProposalPayload{}
+	if err := proto.Unmarshal(prop.Payload, payload); err != nil {
+		return errorResponse(errors.Wrap(err, "could not unmarshal chaincode proposal payload"))
+	}
+
+	cis := &pb.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(payload.Input, cis); err != nil {
+		return errorResponse(errors.Wrap(err, "could not unmarshal chaincode invocation spec"))
+	}
+
+	if err := s.CheckACL(signedProp, chdr, shdr, &pb.ChaincodeHeaderExtension{ChaincodeId: cis.ChaincodeSpec.ChaincodeId}); err != nil {
+		return errorResponse(errors.Wrap(err, "ACL check failed"))
+	}
+
+	txid := chdr.TxId
+	txsim, err := s.GetTxSimulator(chdr.ChannelId, txid)
+	if err != nil {
+		return errorResponse(errors.Wrap(err, "could not create transaction simulator"))
+	}
+	defer txsim.Done()
+
+	txParams := &ccprovider.TransactionParams{
+		TxID:        txid,
+		ChannelID:   chdr.ChannelId,
+		SignedProp:  signedProp,
+		Proposal:    prop,
+		TXSimulator: txsim,
+	}
+
+	cid := cis.ChaincodeSpec.ChaincodeId
+	resps, _, err := s.Execute(ctx, txParams, chdr.ChannelId, cid.Name, cid.Version, txid,
+		&pb.SignedProposals{}, []*pb.Proposal{prop}, []*pb.ChaincodeInput{cis.ChaincodeSpec.Input})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	return &pb.ProposalResponse{Response: resps[0]}
+}
+
+// ProcessProposalStream implements the bidirectional streaming RPC added
+// alongside the unary Endorser service: rpc ProcessProposalStream(stream
+// StreamedProposal) returns (stream StreamedProposalResponse). Each
+// inbound proposal is handed to the same worker pool backing the batched
+// Execute path, bounded by MaxInflight concurrent proposals, and its
+// StreamedProposalResponse is emitted as soon as the goroutine completes
+// - out of order, correlated back to the request by the client-supplied
+// CorrelationId rather than response order.
+//
+// ctx is derived from the stream's own context via context.WithCancel so
+// that however this function returns - client disconnect, stream.Send
+// error, or the recv loop ending - every blocked worker goroutine is
+// released instead of leaking.
+func (s *SupportImpl) ProcessProposalStream(stream pb.EndorserStream_ProcessProposalStreamServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	maxInflight := s.MaxInflight
+	if maxInflight <= 0 {
+		maxInflight = defaultMaxInflight
+	}
+	sem := make(chan struct{}, maxInflight)
+
+	responses := make(chan *pb.StreamedProposalResponse)
+	var pending sync.WaitGroup
+
+	go func() {
+	recvLoop:
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				break recvLoop
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				break recvLoop
+			}
+
+			pending.Add(1)
+			go func(msg *pb.StreamedProposal) {
+				defer pending.Done()
+				defer func() { <-sem }()
+
+				resp, err := s.ProcessProposal(ctx, msg.Proposal)
+				if err != nil {
+					resp = errorResponse(err)
+				}
+
+				streamed := &pb.StreamedProposalResponse{
+					Response:      resp,
+					CorrelationId: msg.CorrelationId,
+				}
+				select {
+				case responses <- streamed:
+				case <-ctx.Done():
+				}
+			}(msg)
+		}
+		pending.Wait()
+		close(responses)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case resp, ok := <-responses:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ProcessProposal implements the existing unary RPC by running the
+// proposal through s.filterChain, so AuthFilters see every proposal
+// before it reaches processSignedProposal - the same entry point
+// ProcessProposalStream uses.
+func (s *SupportImpl) ProcessProposal(ctx context.Context, signedProp *pb.SignedProposal) (*pb.ProposalResponse, error) {
+	return s.filterChain.ProcessProposal(ctx, signedProp)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorser
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// fakeProcessProposalStream is a minimal
+// pb.EndorserStream_ProcessProposalStreamServer backed by channels, so
+// tests can drive ProcessProposalStream without a real gRPC connection.
+type fakeProcessProposalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	in  chan *pb.StreamedProposal
+	out chan *pb.StreamedProposalResponse
+}
+
+func (f *fakeProcessProposalStream) Context() context.Context { return f.ctx }
+
+func (f *fakeProcessProposalStream) Recv() (*pb.StreamedProposal, error) {
+	msg, ok := <-f.in
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+func (f *fakeProcessProposalStream) Send(resp *pb.StreamedProposalResponse) error {
+	f.out <- resp
+	return nil
+}
+
+func TestProcessProposalStreamCorrelatesResponsesToRequests(t *testing.T) {
+	origProcessSignedProposal := processSignedProposal
+	defer func() { processSignedProposal = origProcessSignedProposal }()
+	processSignedProposal = func(ctx context.Context, s *SupportImpl, signedProp *pb.SignedProposal) *pb.ProposalResponse {
+		// Vary the delay so responses can complete out of send order,
+		// exercising the correlation_id matching rather than send order.
+		time.Sleep(time.Duration(len(signedProp.ProposalBytes)) * time.Millisecond)
+		return &pb.ProposalResponse{Response: &pb.Response{Status: 200}}
+	}
+
+	stream := &fakeProcessProposalStream{
+		ctx: context.Background(),
+		in:  make(chan *pb.StreamedProposal, 3),
+		out: make(chan *pb.StreamedProposalResponse, 3),
+	}
+	ids := []string{"first", "second", "third"}
+	delays := []int{3, 1, 2}
+	for i, id := range ids {
+		stream.in <- &pb.StreamedProposal{
+			Proposal:      &pb.SignedProposal{ProposalBytes: make([]byte, delays[i])},
+			CorrelationId: id,
+		}
+	}
+	close(stream.in)
+
+	s := &SupportImpl{}
+	s.SetAuthFilters(nil)
+	done := make(chan error, 1)
+	go func() { done <- s.ProcessProposalStream(stream) }()
+
+	require.NoError(t, <-done)
+	close(stream.out)
+
+	seen := make(map[string]bool)
+	for resp := range stream.out {
+		seen[resp.CorrelationId] = true
+	}
+	assert.Equal(t, map[string]bool{"first": true, "second": true, "third": true}, seen)
+}
+
+func TestProcessProposalStreamBoundsInflightProposals(t *testing.T) {
+	var current, maxSeen int32
+
+	origProcessSignedProposal := processSignedProposal
+	defer func() { processSignedProposal = origProcessSignedProposal }()
+	processSignedProposal = func(ctx context.Context, s *SupportImpl, signedProp *pb.SignedProposal) *pb.ProposalResponse {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			seen := atomic.LoadInt32(&maxSeen)
+			if n <= seen || atomic.CompareAndSwapInt32(&maxSeen, seen, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+		return &pb.ProposalResponse{Response: &pb.Response{Status: 200}}
+	}
+
+	stream := &fakeProcessProposalStream{
+		ctx: context.Background(),
+		in:  make(chan *pb.StreamedProposal, 5),
+		out: make(chan *pb.StreamedProposalResponse, 5),
+	}
+	for i := 0; i < 5; i++ {
+		stream.in <- &pb.StreamedProposal{Proposal: &pb.SignedProposal{}, CorrelationId: "x"}
+	}
+	close(stream.in)
+
+	s := &SupportImpl{MaxInflight: 1}
+	s.SetAuthFilters(nil)
+	done := make(chan error, 1)
+	go func() { done <- s.ProcessProposalStream(stream) }()
+
+	require.NoError(t, <-done)
+	assert.EqualValues(t, 1, maxSeen)
+}
+
+func TestProcessProposalStreamStopsOnCancellation(t *testing.T) {
+	origProcessSignedProposal := processSignedProposal
+	defer func() { processSignedProposal = origProcessSignedProposal }()
+	processSignedProposal = func(ctx context.Context, s *SupportImpl, signedProp *pb.SignedProposal) *pb.ProposalResponse {
+		t.Fatal("processSignedProposal should not run when the stream is cancelled before any proposal arrives")
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &fakeProcessProposalStream{
+		ctx: ctx,
+		// Recv blocks forever: nothing is ever sent on in, and it is never
+		// closed, so the only way ProcessProposalStream returns is via ctx.
+		in:  make(chan *pb.StreamedProposal),
+		out: make(chan *pb.StreamedProposalResponse),
+	}
+
+	support := &SupportImpl{}
+	done := make(chan error, 1)
+	go func() { done <- support.ProcessProposalStream(stream) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		require.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("ProcessProposalStream did not return after the stream's context was cancelled")
+	}
+}
@@ -7,14 +7,20 @@ SPDX-License-Identifier: Apache-2.0
 package endorser
 
 import (
+	"context"
+	"crypto/sha256"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/core/aclmgmt"
 	"github.com/hyperledger/fabric/core/aclmgmt/resources"
 	"github.com/hyperledger/fabric/core/chaincode"
 	"github.com/hyperledger/fabric/core/common/ccprovider"
+	"github.com/hyperledger/fabric/core/handlers/auth"
 	"github.com/hyperledger/fabric/core/handlers/decoration"
 	. "github.com/hyperledger/fabric/core/handlers/endorsement/api/identities"
 	"github.com/hyperledger/fabric/core/handlers/library"
@@ -24,18 +30,125 @@ import (
 	"github.com/hyperledger/fabric/protos/common"
 	pb "github.com/hyperledger/fabric/protos/peer"
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 )
 
+// stopGracePeriod bounds how long executeOnce/executeReplicated wait for
+// an in-flight goroutine to actually return after Stop is called on a
+// timed-out/cancelled execution, before giving up on reading its result
+// slot. Reading resps/errs/results right after calling Stop - without
+// this second wait - would race with the goroutine still writing them.
+const stopGracePeriod = 5 * time.Second
+
 // SupportImpl provides an implementation of the endorser.Support interface
 // issuing calls to various static methods of the peer
 type SupportImpl struct {
 	*PluginEndorser
 	crypto.SignerSupport
-	Peer             peer.Operations
-	PeerSupport      peer.Support
-	ChaincodeSupport []*chaincode.ChaincodeSupport
-	SysCCProvider    *scc.Provider
-	ACLProvider      aclmgmt.ACLProvider
+	Peer                      peer.Operations
+	PeerSupport               peer.Support
+	Registrar                 *ChaincodeSupportRegistrar
+	DispatchPolicy            DispatchPolicy
+	SysCCProvider             *scc.Provider
+	ACLProvider               aclmgmt.ACLProvider
+	ChaincodeDefinitionGetter ChaincodeDefinitionGetter
+	InstantiatedCCStore       InstantiatedCCStore
+	Decorators                []decoration.Decorator
+	AuthFilters               []auth.Filter
+	ReplicationFactor         int
+	ReconciliationPolicy      ReconciliationPolicy
+	MaxInflight               int
+
+	// filterChain is s.AuthFilters wrapped around coreEndorser, rebuilt
+	// whenever AuthFilters changes. ProcessProposal runs through this
+	// instead of calling into the execution engine directly.
+	filterChain pb.EndorserServer
+}
+
+// coreEndorser terminates the auth filter chain with the same
+// proposal-processing entry point ProcessProposal and
+// ProcessProposalStream share, so AuthFilters wrap the real execution
+// engine rather than a second copy of it.
+type coreEndorser struct {
+	support *SupportImpl
+}
+
+func (c *coreEndorser) ProcessProposal(ctx context.Context, signedProp *pb.SignedProposal) (*pb.ProposalResponse, error) {
+	return processSignedProposal(ctx, c.support, signedProp), nil
+}
+
+// rebuildFilterChain wraps coreEndorser with s.AuthFilters, outermost
+// filter first, so every ProcessProposal call runs through the auth
+// chain before reaching the execution engine. It must be called
+// whenever AuthFilters changes.
+func (s *SupportImpl) rebuildFilterChain() {
+	var endorser pb.EndorserServer = &coreEndorser{support: s}
+	for i := len(s.AuthFilters) - 1; i >= 0; i-- {
+		s.AuthFilters[i].Init(endorser)
+		endorser = s.AuthFilters[i]
+	}
+	s.filterChain = endorser
+}
+
+// SupportConfig aggregates the peer-level configuration NewSupportImpl
+// needs to build a SupportImpl's static dependencies once, at startup,
+// instead of re-resolving them on every proposal.
+type SupportConfig struct {
+	LibraryConfig             library.Config
+	Peer                      peer.Operations
+	PeerSupport               peer.Support
+	Registrar                 *ChaincodeSupportRegistrar
+	DispatchPolicy            DispatchPolicy
+	SysCCProvider             *scc.Provider
+	ACLProvider               aclmgmt.ACLProvider
+	ChaincodeDefinitionGetter ChaincodeDefinitionGetter
+	InstantiatedCCStore       InstantiatedCCStore
+}
+
+// NewSupportImpl builds the decorator and auth filter chains a single
+// time from the library registry, rather than looking them up on every
+// call to Execute.
+func NewSupportImpl(cfg SupportConfig) *SupportImpl {
+	registry := library.InitRegistry(cfg.LibraryConfig)
+
+	decorators, _ := registry.Lookup(library.Decoration).([]decoration.Decorator)
+	authFilters, _ := registry.Lookup(library.Auth).([]auth.Filter)
+
+	dispatchPolicy := cfg.DispatchPolicy
+	if dispatchPolicy == nil {
+		dispatchPolicy = RoundRobinDispatchPolicy{}
+	}
+
+	s := &SupportImpl{
+		Peer:                      cfg.Peer,
+		PeerSupport:               cfg.PeerSupport,
+		Registrar:                 cfg.Registrar,
+		DispatchPolicy:            dispatchPolicy,
+		SysCCProvider:             cfg.SysCCProvider,
+		ACLProvider:               cfg.ACLProvider,
+		ChaincodeDefinitionGetter: cfg.ChaincodeDefinitionGetter,
+		InstantiatedCCStore:       cfg.InstantiatedCCStore,
+		Decorators:                decorators,
+		AuthFilters:               authFilters,
+	}
+	s.rebuildFilterChain()
+	return s
+}
+
+// SetDecorators overrides the decorator chain, primarily so unit tests
+// can inject no-op decorators without touching the global library
+// registry.
+func (s *SupportImpl) SetDecorators(decorators []decoration.Decorator) {
+	s.Decorators = decorators
+}
+
+// SetAuthFilters overrides the auth filter chain, primarily so unit
+// tests can inject no-op filters without touching the global library
+// registry. The filter chain backing ProcessProposal is rebuilt
+// immediately so the new filters take effect on the next call.
+func (s *SupportImpl) SetAuthFilters(authFilters []auth.Filter) {
+	s.AuthFilters = authFilters
+	s.rebuildFilterChain()
 }
 
 func (s *SupportImpl) NewQueryCreator(channel string) (QueryCreator, error) {
@@ -115,12 +228,7 @@ func (s *SupportImpl) IsSysCC(name string) bool {
 
 // GetChaincode returns the CCPackage from the fs
 func (s *SupportImpl) GetChaincodeDeploymentSpecFS(cds *pb.ChaincodeDeploymentSpec) (*pb.ChaincodeDeploymentSpec, error) {
-	ccpack, err := ccprovider.GetChaincodeFromFS(cds.ChaincodeSpec.ChaincodeId.Name, cds.ChaincodeSpec.ChaincodeId.Version)
-	if err != nil {
-		return nil, errors.Wrapf(err, "could not get chaincode from fs")
-	}
-
-	return ccpack.GetDepSpec(), nil
+	return s.InstantiatedCCStore.GetChaincodeDeploymentSpecFS(cds)
 }
 
 // ExecuteInit a deployment proposal and return the chaincode response
@@ -129,20 +237,34 @@ func (s *SupportImpl) ExecuteLegacyInit(txParams *ccprovider.TransactionParams,
 		Name:    name,
 		Version: version,
 	}
-	support := s.ChaincodeSupport[0]
+	support := s.Registrar.Default()
 	return support.ExecuteLegacyInit(txParams, cccid, cds)
 }
 
-// Execute a proposal and return the chaincode response
-//　执行提案并返回链码响应
-func (s *SupportImpl) Execute(txParams *ccprovider.TransactionParams, cid, name, version, txid string, signedProp *pb.SignedProposals, props []*pb.Proposal, inputs []*pb.ChaincodeInput) ([]*pb.Response, []*pb.ChaincodeEvent, error) {
-	var resps = *new([]*pb.Response)
-	var ccEvents = *new([]*pb.ChaincodeEvent)
-	var err error
+// supportFor picks the ChaincodeSupport that should run proposal index of
+// a batch: system chaincode calls (cscc, lscc, GetConfigBlock) always go
+// to the designated system support, everything else goes through
+// DispatchPolicy.
+func (s *SupportImpl) supportFor(name string, input *pb.ChaincodeInput, index int, channelID, txid string) (*chaincode.ChaincodeSupport, error) {
+	if name == "cscc" || name == "lscc" || (len(input.Args) > 0 && string(input.Args[0]) == "GetConfigBlock") {
+		support := s.Registrar.ForSystemCC()
+		if support == nil {
+			return nil, errors.New("no chaincode support registered for system chaincode")
+		}
+		return support, nil
+	}
+	return s.DispatchPolicy.Choose(s.Registrar, index, channelID, txid)
+}
 
+// Execute a proposal and return the chaincode response. ctx is plumbed
+// from the originating RPC (unary or stream) so that a client
+// disconnect/cancellation aborts in-flight simulations, not just the
+// server-side peer.chaincode.executetimeout deadline.
+//　执行提案并返回链码响应
+func (s *SupportImpl) Execute(ctx context.Context, txParams *ccprovider.TransactionParams, cid, name, version, txid string, signedProp *pb.SignedProposals, props []*pb.Proposal, inputs []*pb.ChaincodeInput) ([]*pb.Response, []*pb.ChaincodeEvent, error) {
 	// decorate the chaincode input
-	// 单例装饰器
-	decorators := library.InitRegistry(library.Config{}).Lookup(library.Decoration).([]decoration.Decorator)
+	// 装饰器链已在 NewSupportImpl 中构建一次，这里直接复用
+	decorators := s.Decorators
 
 	for i, _ := range inputs {
 		input := inputs[i]
@@ -152,66 +274,223 @@ func (s *SupportImpl) Execute(txParams *ccprovider.TransactionParams, cid, name,
 		inputs[i] = input // 将装饰后的input重新放入inputs
 	}
 
-	cRes := make(chan *pb.Response, 2)
-	cCCEvt := make(chan *pb.ChaincodeEvent, 2)
-	cErr := make(chan error, 2)
+	// 回放执行：系统链码跳过，避免浪费 K 倍算力。txParams.TXSimulator is set
+	// for every proposal this package endorses (see processSignedProposal),
+	// so it cannot distinguish a read-only invocation from a write - there
+	// is no such signal available this early, before the chaincode runs.
+	if s.ReplicationFactor > 1 && !s.IsSysCC(name) {
+		return s.executeReplicated(ctx, txParams, name, version, txid, inputs)
+	}
 
-	// 并行执行交易
-	for i, _ := range inputs {
+	return s.executeOnce(ctx, txParams, name, version, txid, inputs)
+}
+
+// executeOnce dispatches each input to a single ChaincodeSupport chosen by
+// supportFor, one goroutine per input, and waits for all of them to
+// finish or for ctx (bounded by peer.chaincode.executetimeout) to expire -
+// whichever comes first. Results are written into slices indexed by
+// input position, so input order is preserved regardless of which
+// goroutine finishes first, and every goroutine's error is kept rather
+// than the last one overwriting index 0.
+func (s *SupportImpl) executeOnce(ctx context.Context, txParams *ccprovider.TransactionParams, name, version, txid string, inputs []*pb.ChaincodeInput) ([]*pb.Response, []*pb.ChaincodeEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, viper.GetDuration("peer.chaincode.executetimeout"))
+	defer cancel()
+
+	resps := make([]*pb.Response, len(inputs))
+	ccEvents := make([]*pb.ChaincodeEvent, len(inputs))
+	errs := make([]error, len(inputs))
+	supports := make([]*chaincode.ChaincodeSupport, len(inputs))
+	cccids := make([]*ccprovider.CCContext, len(inputs))
+
+	var wg sync.WaitGroup
+	for i := range inputs {
 		// 此support必须每次循环时创建一个示例，不能在循环时获取for中的value值，因为在for中每次都是为同一个对象赋值
-		support := s.ChaincodeSupport[i]
-		// 获取配置区块的交易必须使用第一个 ChaincodeSupport，因为创建的其他 ChaincodeSupport 功能不完善。
-		if string(inputs[i].Args[0]) == "GetConfigBlock" {
-			support = s.ChaincodeSupport[len(s.ChaincodeSupport)-1]
+		support, err := s.supportFor(name, inputs[i], i, txParams.ChannelID, txid)
+		if err != nil {
+			return nil, nil, err
 		}
+		supports[i] = support
 
-		go func(index int, s *chaincode.ChaincodeSupport) {
-			version2 := version
-			if (name != "cscc") && (name != "lscc") {
-				version2 = version + "-" + s.CCContainerName
-			}
-			// 创建链码上下文对象
-			cccid := &ccprovider.CCContext{
-				Name:    name,
-				Version: version2,
-			}
-			response, event, err := s.Execute(txParams, cccid, inputs[index])
-			cCCEvt <- event
-			cErr <- err
-			cRes <- response
-		}(i, support)
+		version2 := version
+		if name != "cscc" && name != "lscc" {
+			version2 = version + "-" + support.CCContainerName
+		}
+		cccids[i] = &ccprovider.CCContext{Name: name, Version: version2}
+
+		wg.Add(1)
+		go func(index int, support *chaincode.ChaincodeSupport, cccid *ccprovider.CCContext) {
+			defer wg.Done()
+			response, event, err := support.Execute(txParams, cccid, inputs[index])
+			resps[index] = response
+			ccEvents[index] = event
+			errs[index] = err
+		}(i, support, cccids[i])
 	}
 
-EXIT:
-	for {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// 中止仍在执行的模拟，避免 goroutine 和 channel 泄漏。Stop is called
+		// unconditionally (not gated on reading resps[i]/errs[i], which
+		// would race with the goroutines still writing them) and then we
+		// wait again, bounded by stopGracePeriod, so resps/ccEvents are
+		// only read below once every goroutine has actually returned.
+		for i, support := range supports {
+			_ = support.Stop(cccids[i])
+		}
 		select {
-		case err = <-cErr:
-			res := <-cRes
-			evt := <-cCCEvt
-			resps = append(resps, res)
-			ccEvents = append(ccEvents, evt)
-			// 当获取的响应多于链码的输入参数时退出
-			if len(resps) >= len(inputs) {
-				break EXIT
-			}
+		case <-done:
+		case <-time.After(stopGracePeriod):
+			return nil, nil, errors.Wrap(ctx.Err(), "proposal execution timed out or was cancelled, and one or more chaincode containers did not stop within the grace period")
+		}
+		return resps, ccEvents, errors.Wrap(ctx.Err(), "proposal execution timed out or was cancelled")
+	}
+
+	var aggregated *multierror.Error
+	for _, err := range errs {
+		if err != nil {
+			aggregated = multierror.Append(aggregated, err)
+		}
+	}
+	return resps, ccEvents, aggregated.ErrorOrNil()
+}
+
+// executeReplicated runs each input against s.ReplicationFactor distinct
+// ChaincodeSupport replicas and reconciles their responses via
+// s.ReconciliationPolicy before returning, so non-determinism inside
+// chaincode (map iteration, time, random) is caught here instead of
+// surfacing later as a validation-phase disagreement. s.ReconciliationPolicy
+// defaults to a majority QuorumReconciliationPolicy when left nil, the same
+// way s.DispatchPolicy defaults in NewSupportImpl. It is bounded by
+// the same ctx/peer.chaincode.executetimeout deadline and Stop-on-cancel
+// treatment as executeOnce, so a single hung replica can no longer block
+// Execute forever.
+func (s *SupportImpl) executeReplicated(ctx context.Context, txParams *ccprovider.TransactionParams, name, version, txid string, inputs []*pb.ChaincodeInput) ([]*pb.Response, []*pb.ChaincodeEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, viper.GetDuration("peer.chaincode.executetimeout"))
+	defer cancel()
+
+	names := s.Registrar.Names()
+	if len(names) < s.ReplicationFactor {
+		return nil, nil, errors.Errorf("replication factor %d exceeds %d registered chaincode supports", s.ReplicationFactor, len(names))
+	}
+	replicas := names[:s.ReplicationFactor]
+
+	// results, supports and cccids hold one entry per (input, replica)
+	// pair, indexed [i][k], so a single WaitGroup and a single Stop-on-cancel pass
+	// cover every goroutine, the same pattern executeOnce uses.
+	results := make([][]replicaResult, len(inputs))
+	supports := make([][]*chaincode.ChaincodeSupport, len(inputs))
+	cccids := make([][]*ccprovider.CCContext, len(inputs))
+	for i := range inputs {
+		results[i] = make([]replicaResult, len(replicas))
+		supports[i] = make([]*chaincode.ChaincodeSupport, len(replicas))
+		cccids[i] = make([]*ccprovider.CCContext, len(replicas))
+	}
+
+	var wg sync.WaitGroup
+	for i := range inputs {
+		for k, supportName := range replicas {
+			support, err := s.Registrar.Get(supportName)
 			if err != nil {
-				resps[0] = res
-				ccEvents[0] = evt
+				return nil, nil, err
 			}
+			supports[i][k] = support
+			cccids[i][k] = &ccprovider.CCContext{Name: name, Version: version + "-" + support.CCContainerName}
+
+			wg.Add(1)
+			go func(i, k int, support *chaincode.ChaincodeSupport, cccid *ccprovider.CCContext, supportName string) {
+				defer wg.Done()
+				results[i][k] = s.executeReplica(txParams, support, cccid, supportName, inputs[i])
+			}(i, k, support, cccids[i][k], supportName)
 		}
 	}
 
-	//for i := 1; i < cap(cRes); i++ {
-	//	resps = append(resps, <- cRes)
-	//	ccEvents = append(ccEvents, <- cCCEvt)
-	//}
-	return resps, ccEvents, err
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// 中止仍在执行的模拟，避免 goroutine 和 channel 泄漏。Stop is called
+		// unconditionally (not gated on reading results[i][k], which would
+		// race with the goroutines still writing it) and then we wait
+		// again, bounded by stopGracePeriod, before any further read of
+		// results is attempted.
+		for i := range supports {
+			for k, support := range supports[i] {
+				_ = support.Stop(cccids[i][k])
+			}
+		}
+		select {
+		case <-done:
+		case <-time.After(stopGracePeriod):
+			return nil, nil, errors.Wrap(ctx.Err(), "replicated proposal execution timed out or was cancelled, and one or more chaincode containers did not stop within the grace period")
+		}
+		return nil, nil, errors.Wrap(ctx.Err(), "replicated proposal execution timed out or was cancelled")
+	}
+
+	// ReplicationFactor and ReconciliationPolicy are plain exported fields,
+	// not SupportConfig options, so nothing stops a caller from setting one
+	// without the other; default here rather than in NewSupportImpl so
+	// ReplicationFactor set after construction is still covered.
+	reconciliationPolicy := s.ReconciliationPolicy
+	if reconciliationPolicy == nil {
+		reconciliationPolicy = &QuorumReconciliationPolicy{Quorum: s.ReplicationFactor/2 + 1}
+	}
+
+	resps := make([]*pb.Response, len(inputs))
+	ccEvents := make([]*pb.ChaincodeEvent, len(inputs))
+	for i := range inputs {
+		response, event, err := reconciliationPolicy.Reconcile(results[i])
+		if err != nil {
+			return resps, ccEvents, err
+		}
+		resps[i] = response
+		ccEvents[i] = event
+	}
+	return resps, ccEvents, nil
+}
+
+// executeReplica runs a single input against a single, already-resolved
+// ChaincodeSupport replica and reduces the outcome to the digests
+// QuorumReconciliationPolicy compares.
+func (s *SupportImpl) executeReplica(txParams *ccprovider.TransactionParams, support *chaincode.ChaincodeSupport, cccid *ccprovider.CCContext, supportName string, input *pb.ChaincodeInput) replicaResult {
+	response, event, err := support.Execute(txParams, cccid, input)
+
+	var rwsetHash [32]byte
+	if simRes, simErr := txParams.TXSimulator.GetTxSimulationResults(); simErr == nil && simRes != nil {
+		if rwsetBytes, mErr := simRes.GetPubSimulationBytes(); mErr == nil {
+			rwsetHash = sha256.Sum256(rwsetBytes)
+		}
+	}
+
+	var payloadHash [32]byte
+	if response != nil {
+		payloadHash = sha256.Sum256(response.Payload)
+	}
+
+	return replicaResult{
+		supportName: supportName,
+		response:    response,
+		event:       event,
+		payloadHash: payloadHash,
+		rwsetHash:   rwsetHash,
+		err:         err,
+	}
 }
 
 // GetChaincodeDefinition returns ccprovider.ChaincodeDefinition for the chaincode with the supplied name
 func (s *SupportImpl) GetChaincodeDefinition(chaincodeName string, txsim ledger.QueryExecutor) (ccprovider.ChaincodeDefinition, error) {
-	support := s.ChaincodeSupport[0]
-	return support.Lifecycle.ChaincodeDefinition(chaincodeName, txsim)
+	return s.ChaincodeDefinitionGetter.ChaincodeDefinition(chaincodeName, txsim)
 }
 
 // CheckACL checks the ACL for the resource for the Channel using the
@@ -223,19 +502,13 @@ func (s *SupportImpl) CheckACL(signedProp *pb.SignedProposal, chdr *common.Chann
 // IsJavaCC returns true if the CDS package bytes describe a chaincode
 // that requires the java runtime environment to execute
 func (s *SupportImpl) IsJavaCC(buf []byte) (bool, error) {
-	//the inner dep spec will contain the type
-	ccpack, err := ccprovider.GetCCPackage(buf)
-	if err != nil {
-		return false, err
-	}
-	cds := ccpack.GetDepSpec()
-	return (cds.ChaincodeSpec.Type == pb.ChaincodeSpec_JAVA), nil
+	return s.InstantiatedCCStore.IsJavaCC(buf)
 }
 
 // CheckInstantiationPolicy returns an error if the instantiation in the supplied
 // ChaincodeDefinition differs from the instantiation policy stored on the ledger
 func (s *SupportImpl) CheckInstantiationPolicy(name, version string, cd ccprovider.ChaincodeDefinition) error {
-	return ccprovider.CheckInstantiationPolicy(name, version, cd.(*ccprovider.ChaincodeData))
+	return s.InstantiatedCCStore.CheckInstantiationPolicy(name, version, cd)
 }
 
 // GetApplicationConfig returns the configtxapplication.SharedConfig for the Channel
@@ -0,0 +1,77 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/handlers/auth"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tracingFilter is a minimal auth.Filter that records its own name in a
+// shared trace slice before calling through to the next filter, so tests
+// can assert on the order the chain actually invokes filters in.
+type tracingFilter struct {
+	name  string
+	next  pb.EndorserServer
+	trace *[]string
+}
+
+func (f *tracingFilter) Init(next pb.EndorserServer) {
+	f.next = next
+}
+
+func (f *tracingFilter) ProcessProposal(ctx context.Context, signedProp *pb.SignedProposal) (*pb.ProposalResponse, error) {
+	*f.trace = append(*f.trace, f.name)
+	return f.next.ProcessProposal(ctx, signedProp)
+}
+
+func TestSetAuthFiltersRebuildsChain(t *testing.T) {
+	var trace []string
+
+	origProcessSignedProposal := processSignedProposal
+	defer func() { processSignedProposal = origProcessSignedProposal }()
+	processSignedProposal = func(ctx context.Context, s *SupportImpl, signedProp *pb.SignedProposal) *pb.ProposalResponse {
+		trace = append(trace, "core")
+		return &pb.ProposalResponse{}
+	}
+
+	a := &tracingFilter{name: "a", trace: &trace}
+	b := &tracingFilter{name: "b", trace: &trace}
+	s := &SupportImpl{}
+
+	t.Run("no filters goes straight to the execution engine", func(t *testing.T) {
+		s.SetAuthFilters(nil)
+		trace = nil
+
+		_, err := s.ProcessProposal(context.Background(), &pb.SignedProposal{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"core"}, trace)
+	})
+
+	t.Run("filters run outermost-first, ending at the execution engine", func(t *testing.T) {
+		s.SetAuthFilters([]auth.Filter{a, b})
+		trace = nil
+
+		_, err := s.ProcessProposal(context.Background(), &pb.SignedProposal{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "core"}, trace)
+	})
+
+	t.Run("setting new filters rebuilds the chain instead of appending", func(t *testing.T) {
+		s.SetAuthFilters([]auth.Filter{b})
+		trace = nil
+
+		_, err := s.ProcessProposal(context.Background(), &pb.SignedProposal{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b", "core"}, trace)
+	})
+}